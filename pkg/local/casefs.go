@@ -0,0 +1,32 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const caseProbeFile = ".lakefs_case_probe"
+
+// DetectCaseInsensitive reports whether dir sits on a case-insensitive (but
+// typically still case-preserving) filesystem, such as the default macOS
+// and Windows volumes. It does so the same way golang.org/x/tools' gopls
+// cache probes its module cache directory: create a file, then try to stat
+// it back under an upper-cased name. If that succeeds, the filesystem folds
+// case for lookups even though it stored the name as given.
+//
+// lakeFS paths are always case-sensitive, so callers that detect true here
+// should diff and reconcile with case-folded comparisons to avoid treating
+// a mere case difference as an add+remove pair.
+func DetectCaseInsensitive(dir string) bool {
+	probePath := filepath.Join(dir, caseProbeFile)
+	f, err := os.OpenFile(probePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return false
+	}
+	_ = f.Close()
+	defer func() { _ = os.Remove(probePath) }()
+
+	_, err = os.Stat(filepath.Join(dir, strings.ToUpper(caseProbeFile)))
+	return err == nil
+}
@@ -0,0 +1,63 @@
+package local_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/require"
+	"github.com/treeverse/lakefs/pkg/api/apigen"
+	"github.com/treeverse/lakefs/pkg/local"
+)
+
+func TestDetectCaseInsensitive(t *testing.T) {
+	dir := t.TempDir()
+
+	// Just exercise the probe: it must not leave its scratch file behind,
+	// and must be safe to call repeatedly (a prior run's leftover file
+	// shouldn't make a later call misreport or fail).
+	_ = local.DetectCaseInsensitive(dir)
+	_ = local.DetectCaseInsensitive(dir)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries, "DetectCaseInsensitive must clean up its probe file")
+}
+
+func TestDiffLocalCaseConflictPrefersExactMatch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "f.txt"), []byte("abc"), 0o644))
+	modTime := time.Unix(diffTestCorrectTime, 0)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "f.txt"), modTime, modTime))
+
+	remote := []apigen.ObjectStats{
+		{Path: "F.txt", SizeBytes: swag.Int64(3), Mtime: diffTestCorrectTime},
+		{Path: "f.txt", SizeBytes: swag.Int64(3), Mtime: diffTestCorrectTime},
+	}
+	lc := make(chan apigen.ObjectStats, len(remote))
+	for _, o := range remote {
+		lc <- o
+	}
+	close(lc)
+
+	changes, err := local.DiffLocalWithHeadOpts(lc, dir, local.DiffLocalWithHeadOptions{CaseInsensitiveFS: true})
+	require.NoError(t, err)
+
+	var conflicts, removed, modified int
+	for _, c := range changes {
+		switch c.Type {
+		case local.ChangeTypeCaseConflict:
+			conflicts++
+			require.Equal(t, "F.txt", c.Path, "the conflict must be reported against the entry that isn't the exact match")
+		case local.ChangeTypeRemoved:
+			removed++
+		case local.ChangeTypeModified:
+			modified++
+		}
+	}
+	require.Equal(t, 1, conflicts, "expected exactly one case conflict")
+	require.Zero(t, removed, "the exact match must not also be reported removed")
+	require.Zero(t, modified, "the local file matches its exact remote counterpart and shouldn't be reported modified")
+}
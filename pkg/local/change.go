@@ -0,0 +1,40 @@
+package local
+
+// ChangeType represents the kind of difference found between a local file
+// and its remote counterpart.
+type ChangeType int
+
+const (
+	ChangeTypeAdded ChangeType = iota
+	ChangeTypeRemoved
+	ChangeTypeModified
+
+	// ChangeTypeCaseConflict marks a path that exists both locally and
+	// remotely but differs only in case, e.g. local "sub/F.txt" vs remote
+	// "sub/f.txt". It's only ever produced when diffing against a
+	// case-insensitive local filesystem (see DetectCaseInsensitive): on a
+	// case-sensitive one, these are just two distinct paths.
+	ChangeTypeCaseConflict
+)
+
+func (c ChangeType) String() string {
+	switch c {
+	case ChangeTypeAdded:
+		return "added"
+	case ChangeTypeRemoved:
+		return "removed"
+	case ChangeTypeModified:
+		return "modified"
+	case ChangeTypeCaseConflict:
+		return "case conflict"
+	default:
+		return "unknown"
+	}
+}
+
+// Change describes a single difference between the local working directory
+// and the HEAD of a branch, as produced by DiffLocalWithHead.
+type Change struct {
+	Path string
+	Type ChangeType
+}
@@ -0,0 +1,333 @@
+// Package contenthash maintains a persistent, per-directory cache of file
+// and directory content digests for a local lakeFS checkout, so that
+// operations which need to know "did this change" don't have to re-hash
+// unchanged files on every run. It follows the approach buildkit's
+// contenthash cache uses for build context hashing: stat metadata (mode,
+// size, mtime, ctime) acts as the invalidation key for a cached digest, and
+// directories fold their children's digests into a single digest so whole
+// subtrees can be compared in O(1) once cached.
+package contenthash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FS is the minimal filesystem surface Cache needs in order to read and stat
+// the files it hashes. It's structurally satisfied by local.FS (a superset
+// of these two methods), so a caller's OSFS or synthetic test FS can be
+// passed straight through without this package importing local, which would
+// create an import cycle (local imports contenthash).
+type FS interface {
+	Open(name string) (fs.File, error)
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// osFS is the default FS, used whenever a caller doesn't inject one.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error)     { return os.Open(name) }
+func (osFS) Lstat(name string) (fs.FileInfo, error) { return os.Lstat(name) }
+
+// CacheDir is the directory, relative to a checkout's root, that the cache
+// snapshot is persisted under.
+const CacheDir = ".lakefs"
+
+// CacheFile is the name of the persisted snapshot within CacheDir.
+const CacheFile = "contenthash"
+
+// record is the on-disk representation of a single tree entry, flattened to
+// a slash-separated path so the snapshot can be stored as a flat JSON array
+// instead of a nested structure.
+type record struct {
+	Path       string `json:"path"`
+	IsDir      bool   `json:"is_dir"`
+	Mode       uint32 `json:"mode"`
+	Size       int64  `json:"size"`
+	ModTime    int64  `json:"mod_time"`
+	ChangeTime int64  `json:"change_time"`
+	Digest     string `json:"digest"`
+}
+
+// Cache is a lazily-loaded, persistent content digest cache rooted at a
+// single local checkout. It is safe for concurrent use.
+type Cache struct {
+	mu       sync.Mutex
+	rootPath string
+	fsys     FS
+	tree     *tree
+	loaded   bool
+}
+
+// New returns a Cache persisted under rootPath's CacheDir. The cache is not
+// read from disk until it is first used. fsys, if given, is the FS files are
+// read and stat'd through when computing digests; it defaults to the real
+// filesystem. The cache's own snapshot under CacheDir is always persisted to
+// the real filesystem regardless of fsys, since it's lakeFS's own bookkeeping
+// rather than part of the tree being hashed.
+func New(rootPath string, fsys ...FS) *Cache {
+	return &Cache{rootPath: rootPath, fsys: pickFS(fsys)}
+}
+
+func pickFS(fsys []FS) FS {
+	if len(fsys) > 0 && fsys[0] != nil {
+		return fsys[0]
+	}
+	return osFS{}
+}
+
+func (c *Cache) snapshotPath() string {
+	return filepath.Join(c.rootPath, CacheDir, CacheFile)
+}
+
+// ensureLoaded lazily reads the persisted snapshot, if any, on first use.
+// Missing or unreadable snapshots are treated as an empty cache: content
+// hashing always falls back correctly, it's just slower the first time.
+func (c *Cache) ensureLoaded() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	c.tree = newTree()
+
+	f, err := os.Open(c.snapshotPath())
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []record
+	if err := json.NewDecoder(f).Decode(&records); err != nil {
+		return
+	}
+	for _, r := range records {
+		c.tree = c.tree.with(r.Path, &entry{
+			name:       filepath.Base(r.Path),
+			isDir:      r.IsDir,
+			mode:       r.Mode,
+			size:       r.Size,
+			modTime:    r.ModTime,
+			changeTime: r.ChangeTime,
+			digest:     r.Digest,
+		})
+	}
+}
+
+// Save atomically snapshots the cache to disk: it writes to a temp file in
+// the same directory and renames it over the previous snapshot, so a reader
+// never observes a partially written cache.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ensureLoaded()
+
+	dir := filepath.Join(c.rootPath, CacheDir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("create cache dir: %w", err)
+	}
+
+	var records []record
+	flatten("", c.tree.root, &records)
+
+	tmp, err := os.CreateTemp(dir, CacheFile+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create cache snapshot: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	if err := json.NewEncoder(tmp).Encode(records); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("encode cache snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close cache snapshot: %w", err)
+	}
+	if err := os.Rename(tmpName, c.snapshotPath()); err != nil {
+		return fmt.Errorf("commit cache snapshot: %w", err)
+	}
+	return nil
+}
+
+func flatten(path string, e *entry, out *[]record) {
+	if path != "" {
+		*out = append(*out, record{
+			Path:       path,
+			IsDir:      e.isDir,
+			Mode:       e.mode,
+			Size:       e.size,
+			ModTime:    e.modTime,
+			ChangeTime: e.changeTime,
+			Digest:     e.digest,
+		})
+	}
+	for _, name := range sortedChildNames(e) {
+		child := path + "/" + name
+		if path == "" {
+			child = name
+		}
+		flatten(child, e.children[name], out)
+	}
+}
+
+// statKey captures the stat fields used to decide whether a cached digest is
+// still valid for a path.
+type statKey struct {
+	mode       uint32
+	size       int64
+	modTime    int64
+	changeTime int64
+}
+
+// Checksum returns the content digest of the regular file at the given
+// repo-relative path, whose absolute location is absPath. If the cached
+// entry's stat key matches the file's current stat, the cached digest is
+// returned without reading the file; otherwise the file is hashed and the
+// cache is updated in memory (call Save to persist it).
+func (c *Cache) Checksum(relPath, absPath string) (string, error) {
+	if isUnderCacheDir(relPath) {
+		return "", fmt.Errorf("%q is inside %s, not a trackable path", relPath, CacheDir)
+	}
+
+	info, err := c.fsys.Lstat(absPath)
+	if err != nil {
+		return "", err
+	}
+	key := statKeyOf(info)
+
+	c.mu.Lock()
+	c.ensureLoaded()
+	if cached := c.tree.lookup(relPath); cached != nil && !cached.isDir && sameStat(cached, key) {
+		digest := cached.digest
+		c.mu.Unlock()
+		return digest, nil
+	}
+	c.mu.Unlock()
+
+	digest, err := hashFile(c.fsys, absPath)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.tree = c.tree.with(relPath, &entry{
+		name:       filepath.Base(relPath),
+		mode:       key.mode,
+		size:       key.size,
+		modTime:    key.modTime,
+		changeTime: key.changeTime,
+		digest:     digest,
+	})
+	c.mu.Unlock()
+
+	return digest, nil
+}
+
+// DirectoryDigest returns the digest of the directory at relPath, computed
+// as the SHA-256 of the sorted concatenation of (name, mode, child-digest)
+// over its direct children. Children must already have been checksummed
+// (via Checksum, for files, or DirectoryDigest, for sub-directories) so
+// their digests are present in the tree.
+func (c *Cache) DirectoryDigest(relPath string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ensureLoaded()
+
+	dir := c.tree.lookup(relPath)
+	if dir == nil {
+		dir = &entry{isDir: true, children: map[string]*entry{}}
+	}
+
+	h := sha256.New()
+	for _, name := range sortedChildNames(dir) {
+		child := dir.children[name]
+		fmt.Fprintf(h, "%s\x00%o\x00%s\n", name, child.mode, child.digest)
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+
+	dir.isDir = true
+	dir.digest = digest
+	c.tree = c.tree.with(relPath, dir)
+	return digest, nil
+}
+
+func statKeyOf(info os.FileInfo) statKey {
+	key := statKey{
+		mode:    uint32(info.Mode()),
+		size:    info.Size(),
+		modTime: info.ModTime().UnixNano(),
+	}
+	if ct, ok := changeTime(info); ok {
+		key.changeTime = ct
+	}
+	return key
+}
+
+func sameStat(e *entry, key statKey) bool {
+	return e.mode == key.mode &&
+		e.size == key.size &&
+		e.modTime == key.modTime &&
+		e.changeTime == key.changeTime
+}
+
+func hashFile(fsys FS, absPath string) (string, error) {
+	f, err := fsys.Open(absPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+var (
+	contexts   = map[string]*Cache{}
+	contextsMu sync.Mutex
+)
+
+// GetCacheContext returns the Cache shared by every caller using the same
+// checkout root during this process, creating it on first use. sync,
+// commit and status all call this so a single CLI invocation hashes each
+// file at most once, no matter how many of them touch the checkout. fsys is
+// only consulted the first time a root is seen; once a Cache exists for
+// root, later calls return it unchanged regardless of fsys.
+func GetCacheContext(root string, fsys ...FS) *Cache {
+	root = filepath.Clean(root)
+	contextsMu.Lock()
+	defer contextsMu.Unlock()
+	if c, ok := contexts[root]; ok {
+		return c
+	}
+	c := New(root, fsys...)
+	contexts[root] = c
+	return c
+}
+
+// SetCacheContext installs c as the shared Cache for root, overriding
+// whatever GetCacheContext would otherwise create or return. Mainly useful
+// for tests that want an isolated, pre-populated cache.
+func SetCacheContext(root string, c *Cache) {
+	root = filepath.Clean(root)
+	contextsMu.Lock()
+	defer contextsMu.Unlock()
+	contexts[root] = c
+}
+
+// isUnderCacheDir reports whether relPath falls inside CacheDir, so walkers
+// can skip the cache's own bookkeeping directory the same way they skip
+// .git.
+func isUnderCacheDir(relPath string) bool {
+	return relPath == CacheDir || strings.HasPrefix(relPath, CacheDir+"/")
+}
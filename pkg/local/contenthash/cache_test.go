@@ -0,0 +1,126 @@
+package contenthash_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/treeverse/lakefs/pkg/local/contenthash"
+)
+
+func writeFile(t testing.TB, path, contents string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestCacheChecksumStable(t *testing.T) {
+	dir := t.TempDir()
+	fixedTime := time.Unix(1700000000, 0)
+	path := filepath.Join(dir, "a.txt")
+	writeFile(t, path, "hello", fixedTime)
+
+	c := contenthash.New(dir)
+	d1, err := c.Checksum("a.txt", path)
+	require.NoError(t, err)
+	d2, err := c.Checksum("a.txt", path)
+	require.NoError(t, err)
+	require.Equal(t, d1, d2, "repeated checksums of an unchanged file must agree")
+}
+
+func TestCacheChecksumChangesWithContent(t *testing.T) {
+	dir := t.TempDir()
+	fixedTime := time.Unix(1700000000, 0)
+	path := filepath.Join(dir, "a.txt")
+
+	writeFile(t, path, "hello", fixedTime)
+	c := contenthash.New(dir)
+	before, err := c.Checksum("a.txt", path)
+	require.NoError(t, err)
+
+	// Change content and bump mtime so the stat-based invalidation key
+	// actually changes, the same way a real edit would.
+	writeFile(t, path, "hello, world", fixedTime.Add(time.Second))
+	after, err := c.Checksum("a.txt", path)
+	require.NoError(t, err)
+
+	require.NotEqual(t, before, after, "digest must change when content and stat both change")
+}
+
+func TestCacheChecksumIgnoresStaleDigestOnlyWhenStatUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	fixedTime := time.Unix(1700000000, 0)
+	path := filepath.Join(dir, "a.txt")
+	writeFile(t, path, "hello", fixedTime)
+
+	c := contenthash.New(dir)
+	cached, err := c.Checksum("a.txt", path)
+	require.NoError(t, err)
+
+	// Rewrite with content of the exact same size, but restore the exact
+	// same mtime: the cache should trust its stat-keyed entry and return
+	// the old digest without re-reading the file.
+	writeFile(t, path, "HELLO", fixedTime)
+	stale, err := c.Checksum("a.txt", path)
+	require.NoError(t, err)
+	require.Equal(t, cached, stale, "an unchanged stat key must short-circuit to the cached digest")
+}
+
+func TestCacheSaveAndReload(t *testing.T) {
+	dir := t.TempDir()
+	fixedTime := time.Unix(1700000000, 0)
+	path := filepath.Join(dir, "a.txt")
+	writeFile(t, path, "hello", fixedTime)
+
+	c := contenthash.New(dir)
+	want, err := c.Checksum("a.txt", path)
+	require.NoError(t, err)
+	require.NoError(t, c.Save())
+
+	snapshot := filepath.Join(dir, contenthash.CacheDir, contenthash.CacheFile)
+	_, err = os.Stat(snapshot)
+	require.NoError(t, err, "Save must persist a snapshot under the cache dir")
+
+	reloaded := contenthash.New(dir)
+	got, err := reloaded.Checksum("a.txt", path)
+	require.NoError(t, err)
+	require.Equal(t, want, got, "a freshly loaded cache must agree with the persisted snapshot")
+}
+
+func TestCacheDirectoryDigestIsDeterministicAndSensitiveToChildren(t *testing.T) {
+	dir := t.TempDir()
+	fixedTime := time.Unix(1700000000, 0)
+	pathA := filepath.Join(dir, "a.txt")
+	pathB := filepath.Join(dir, "b.txt")
+	writeFile(t, pathA, "aaa", fixedTime)
+	writeFile(t, pathB, "bbb", fixedTime)
+
+	c := contenthash.New(dir)
+	_, err := c.Checksum("a.txt", pathA)
+	require.NoError(t, err)
+	_, err = c.Checksum("b.txt", pathB)
+	require.NoError(t, err)
+	digest1, err := c.DirectoryDigest("")
+	require.NoError(t, err)
+
+	// Recomputing over the same, unchanged children must be deterministic
+	// regardless of the order Checksum happened to be called in.
+	c2 := contenthash.New(dir)
+	_, err = c2.Checksum("b.txt", pathB)
+	require.NoError(t, err)
+	_, err = c2.Checksum("a.txt", pathA)
+	require.NoError(t, err)
+	digest2, err := c2.DirectoryDigest("")
+	require.NoError(t, err)
+	require.Equal(t, digest1, digest2, "directory digest must not depend on child checksum order")
+
+	// Changing one child's content must change the directory digest.
+	writeFile(t, pathB, "changed", fixedTime.Add(time.Second))
+	_, err = c.Checksum("b.txt", pathB)
+	require.NoError(t, err)
+	digest3, err := c.DirectoryDigest("")
+	require.NoError(t, err)
+	require.NotEqual(t, digest1, digest3, "directory digest must change when a child's digest changes")
+}
@@ -0,0 +1,23 @@
+//go:build darwin || freebsd || netbsd
+
+package contenthash
+
+import (
+	"os"
+	"syscall"
+)
+
+// changeTime extracts ctime from a platform stat_t, when available. ctime
+// changes on metadata-only operations (chmod, rename) that don't bump mtime,
+// which is exactly the kind of false "unchanged" that buildkit's cache also
+// guards against by including it in the invalidation key.
+//
+// darwin, freebsd and netbsd name the field Ctimespec; see stat_ctim.go for
+// the platforms that name it Ctim instead.
+func changeTime(info os.FileInfo) (int64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int64(st.Ctimespec.Sec)*1e9 + int64(st.Ctimespec.Nsec), true
+}
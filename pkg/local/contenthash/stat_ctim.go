@@ -0,0 +1,23 @@
+//go:build linux || openbsd || solaris
+
+package contenthash
+
+import (
+	"os"
+	"syscall"
+)
+
+// changeTime extracts ctime from a platform stat_t, when available. ctime
+// changes on metadata-only operations (chmod, rename) that don't bump mtime,
+// which is exactly the kind of false "unchanged" that buildkit's cache also
+// guards against by including it in the invalidation key.
+//
+// linux, openbsd and solaris all name the field Ctim; darwin, freebsd and
+// netbsd name it Ctimespec instead (see stat_bsd.go).
+func changeTime(info os.FileInfo) (int64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int64(st.Ctim.Sec)*1e9 + int64(st.Ctim.Nsec), true
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package contenthash
+
+import "os"
+
+// changeTime is not exposed by os.FileInfo on Windows; mode, size and mtime
+// remain the invalidation key there.
+func changeTime(_ os.FileInfo) (int64, bool) {
+	return 0, false
+}
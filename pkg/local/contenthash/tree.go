@@ -0,0 +1,104 @@
+package contenthash
+
+import (
+	"sort"
+	"strings"
+)
+
+// entry is a single node in a tree: either a file, recording the digest of
+// its contents, or a directory, recording the digest of its children.
+type entry struct {
+	name       string
+	mode       uint32
+	size       int64
+	modTime    int64
+	changeTime int64
+	digest     string
+	isDir      bool
+	children   map[string]*entry
+}
+
+// tree is an immutable, path-addressed snapshot of a directory's content
+// digests. Every mutation returns a new tree that shares unmodified
+// sub-trees with its predecessor, the same way buildkit's contenthash cache
+// keeps stat-keyed digests cheap to invalidate one path at a time: updating
+// a single leaf only has to rebuild the chain of ancestors down to the
+// root, and readers holding an older tree are unaffected.
+type tree struct {
+	root *entry
+}
+
+func newTree() *tree {
+	return &tree{root: &entry{name: "", isDir: true, children: map[string]*entry{}}}
+}
+
+// splitPath breaks a slash-separated relative path into its components.
+// The root path ("", ".", "/") splits into no components.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" || path == "." {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// lookup returns the entry at path, or nil if it (or an ancestor) is not
+// present in the tree.
+func (t *tree) lookup(path string) *entry {
+	cur := t.root
+	for _, part := range splitPath(path) {
+		if cur == nil || !cur.isDir {
+			return nil
+		}
+		cur = cur.children[part]
+	}
+	return cur
+}
+
+// with returns a new tree with the leaf at path replaced by e, creating any
+// missing intermediate directories. Every ancestor directory along the path
+// is copied and has its digest cleared, so callers must recompute directory
+// digests bottom-up (see Cache.directoryDigest) before persisting the tree.
+func (t *tree) with(path string, e *entry) *tree {
+	parts := splitPath(path)
+	newRoot := copyDir(t.root)
+	cur := newRoot
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			cur.children[part] = e
+			cur.digest = ""
+			break
+		}
+		child, ok := cur.children[part]
+		if !ok || !child.isDir {
+			child = &entry{name: part, isDir: true, children: map[string]*entry{}}
+		} else {
+			child = copyDir(child)
+		}
+		cur.children[part] = child
+		cur.digest = ""
+		cur = child
+	}
+	return &tree{root: newRoot}
+}
+
+func copyDir(e *entry) *entry {
+	cp := *e
+	cp.children = make(map[string]*entry, len(e.children))
+	for k, v := range e.children {
+		cp.children[k] = v
+	}
+	return &cp
+}
+
+// sortedChildNames returns the names of e's children in ascending order, so
+// that directory digests are computed deterministically regardless of map
+// iteration order.
+func sortedChildNames(e *entry) []string {
+	names := make([]string, 0, len(e.children))
+	for name := range e.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
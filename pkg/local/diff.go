@@ -0,0 +1,225 @@
+package local
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/treeverse/lakefs/pkg/api/apigen"
+	"github.com/treeverse/lakefs/pkg/local/contenthash"
+)
+
+// CompareMode selects how DiffLocalWithHead decides whether a file that
+// exists on both sides has been modified.
+type CompareMode int
+
+const (
+	// CompareSizeModTime reports a file as modified when its size or mtime
+	// differs from the remote object's. It's cheap but produces false
+	// positives whenever a checkout, `touch`, or CI cache restore changes
+	// mtimes without changing content.
+	CompareSizeModTime CompareMode = iota
+
+	// CompareContent reports a file as modified only when its content
+	// digest differs from the remote object's checksum, falling back to
+	// size as a fast pre-check. Digests are computed through a
+	// contenthash.Cache so repeat runs only re-hash files whose stat
+	// metadata has actually changed.
+	CompareContent
+)
+
+// DiffLocalWithHeadOptions configures DiffLocalWithHead. The zero value
+// compares by size and mtime, matching prior behavior.
+type DiffLocalWithHeadOptions struct {
+	Compare CompareMode
+
+	// Filter, when non-empty, restricts the diff to paths it matches, on
+	// both the local and remote side: local entries it excludes are never
+	// walked, and remote entries it excludes are never reported removed.
+	Filter Filter
+
+	// CaseInsensitiveFS should be set when localPath sits on a
+	// case-insensitive filesystem (see DetectCaseInsensitive). A local and
+	// remote path that differ only in case are then reported as a single
+	// ChangeTypeCaseConflict, preserving the remote's casing, instead of an
+	// Added/Removed pair.
+	CaseInsensitiveFS bool
+
+	// Resume, when set, makes the diff restartable: every decision is
+	// journaled through it, and a path already marked ResumeStatusDone
+	// against the remote object's current checksum by a prior, interrupted
+	// run is taken on faith instead of being re-reported or re-hashed, so
+	// resuming a CompareContent diff doesn't re-read file contents it
+	// already confirmed. Paths left ResumeStatusInProgress are re-verified
+	// against the current local stat before being retried.
+	Resume *ResumeToken
+
+	// GetFS, when set, overrides the filesystem localPath is walked and
+	// stat'd through, e.g. to diff against an in-memory tree in tests.
+	// Defaults to always returning OSFS.
+	GetFS GetFS
+}
+
+// DiffLocalWithHead compares the contents of the local directory at
+// localPath against the stream of remote object stats in remote, reporting
+// additions, removals and modifications. remote must be sorted in ascending
+// path order; local is walked in the same order via WalkS3.
+func DiffLocalWithHead(remote <-chan apigen.ObjectStats, localPath string) ([]*Change, error) {
+	return DiffLocalWithHeadOpts(remote, localPath, DiffLocalWithHeadOptions{})
+}
+
+// DiffLocalWithHeadOpts is DiffLocalWithHead with explicit options.
+func DiffLocalWithHeadOpts(remote <-chan apigen.ObjectStats, localPath string, opts DiffLocalWithHeadOptions) ([]*Change, error) {
+	var left []apigen.ObjectStats
+	for o := range remote {
+		if len(opts.Filter) > 0 && !opts.Filter.Match(o.Path) {
+			continue
+		}
+		left = append(left, o)
+	}
+
+	getFS := opts.GetFS
+	if getFS == nil {
+		getFS = defaultGetFS
+	}
+	fsys := getFS(localPath)
+
+	var cache *contenthash.Cache
+	if opts.Compare == CompareContent {
+		cache = contenthash.GetCacheContext(localPath, fsys)
+	}
+
+	idx := 0
+	var changes []*Change
+	report := func(c *Change, remoteETag string, localSize int64) error {
+		if opts.Resume == nil {
+			changes = append(changes, c)
+			return nil
+		}
+		if opts.Resume.IsDone(c.Path, remoteETag) {
+			return nil // already handled by a prior, interrupted run
+		}
+		changes = append(changes, c)
+		return opts.Resume.Record(ResumeEntry{
+			Path:       c.Path,
+			Action:     c.Type.String(),
+			RemoteETag: remoteETag,
+			LocalSize:  localSize,
+			Status:     ResumeStatusInProgress,
+		})
+	}
+
+	err := WalkS3FilteredFS(localPath, fsys, opts.Filter, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath := toRelPath(localPath, path)
+
+		for idx < len(left) && left[idx].Path < relPath && !foldMatches(opts.CaseInsensitiveFS, left[idx].Path, relPath) {
+			if err := report(&Change{Path: left[idx].Path, Type: ChangeTypeRemoved}, left[idx].Checksum, 0); err != nil {
+				return err
+			}
+			idx++
+		}
+		if idx < len(left) && left[idx].Path == relPath {
+			if opts.Resume != nil && opts.Resume.IsDone(relPath, left[idx].Checksum) {
+				idx++
+				return nil // a prior, interrupted run already confirmed this path is unmodified
+			}
+			modified, err := isModified(opts.Compare, cache, relPath, path, info, left[idx])
+			if err != nil {
+				return err
+			}
+			if modified {
+				if err := report(&Change{Path: relPath, Type: ChangeTypeModified}, left[idx].Checksum, info.Size()); err != nil {
+					return err
+				}
+			}
+			idx++
+			return nil
+		}
+		if idx < len(left) && foldMatches(opts.CaseInsensitiveFS, left[idx].Path, relPath) {
+			// One or more remote entries differ from relPath only in case.
+			// They sort adjacently, since they agree on every byte up to
+			// the case difference, so scan the whole run: if one of them
+			// is an exact match, it's the real counterpart of this local
+			// file and every other entry in the run is the actual
+			// conflict, not the exact one picked arbitrarily by sort
+			// order. Keep the remote's casing on conflicts so a
+			// reconciler downloading them won't rename the object.
+			end := idx
+			exactIdx := -1
+			for end < len(left) && foldMatches(opts.CaseInsensitiveFS, left[end].Path, relPath) {
+				if left[end].Path == relPath {
+					exactIdx = end
+				}
+				end++
+			}
+			for j := idx; j < end; j++ {
+				if j == exactIdx {
+					continue
+				}
+				if err := report(&Change{Path: left[j].Path, Type: ChangeTypeCaseConflict}, left[j].Checksum, info.Size()); err != nil {
+					return err
+				}
+			}
+			if exactIdx >= 0 && (opts.Resume == nil || !opts.Resume.IsDone(relPath, left[exactIdx].Checksum)) {
+				modified, err := isModified(opts.Compare, cache, relPath, path, info, left[exactIdx])
+				if err != nil {
+					return err
+				}
+				if modified {
+					if err := report(&Change{Path: relPath, Type: ChangeTypeModified}, left[exactIdx].Checksum, info.Size()); err != nil {
+						return err
+					}
+				}
+			}
+			idx = end
+			return nil
+		}
+		return report(&Change{Path: relPath, Type: ChangeTypeAdded}, "", info.Size())
+	})
+	if err != nil {
+		return nil, err
+	}
+	for ; idx < len(left); idx++ {
+		if err := report(&Change{Path: left[idx].Path, Type: ChangeTypeRemoved}, left[idx].Checksum, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if cache != nil {
+		if err := cache.Save(); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// foldMatches reports whether a and b are the same path once case is
+// folded away. It's only meaningful when caseInsensitiveFS is set; on a
+// case-sensitive filesystem, paths differing in case are simply different
+// paths.
+func foldMatches(caseInsensitiveFS bool, a, b string) bool {
+	return caseInsensitiveFS && strings.EqualFold(a, b)
+}
+
+func toRelPath(root, absPath string) string {
+	rel := strings.TrimPrefix(absPath, root)
+	rel = strings.TrimPrefix(rel, string(filepath.Separator))
+	return filepath.ToSlash(rel)
+}
+
+func isModified(mode CompareMode, cache *contenthash.Cache, relPath, absPath string, info fs.FileInfo, stat apigen.ObjectStats) (bool, error) {
+	if stat.SizeBytes != nil && info.Size() != *stat.SizeBytes {
+		return true, nil
+	}
+	if mode != CompareContent || stat.Checksum == "" {
+		return info.ModTime().Unix() != stat.Mtime, nil
+	}
+	digest, err := cache.Checksum(relPath, absPath)
+	if err != nil {
+		return false, err
+	}
+	return digest != stat.Checksum, nil
+}
@@ -0,0 +1,219 @@
+package local
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Filter is an ordered whitelist of gitignore-style glob patterns matched
+// against the repo-relative path of each entry. A plain pattern includes a
+// path; a leading "!" excludes it instead. Patterns are evaluated in order
+// and the last one to match decides the outcome, so a later "!" rule can
+// exclude what an earlier pattern included and vice versa. A path matched
+// by no pattern at all is excluded: Filter is a restrictive whitelist, not
+// a .gitignore-style blocklist. An empty Filter is the one exception and
+// matches everything, since it means "no filter was configured".
+// "**" matches zero or more path components; every other glob metacharacter
+// is matched within a single component, the same as filepath.Match.
+type Filter []string
+
+// Match reports whether path is included by f.
+func (f Filter) Match(path string) bool {
+	if len(f) == 0 {
+		return true
+	}
+	included := false
+	for _, pattern := range f {
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		if matchGlob(pattern, path) {
+			included = !negate
+		}
+	}
+	return included
+}
+
+// prunesDir reports whether f can never include any path beneath dirPath,
+// so a walker can skip descending into it entirely instead of matching
+// every file beneath it one by one.
+//
+// Last-match-wins is evaluated per pattern, from the last pattern back to
+// the first, since a later pattern takes precedence over an earlier one
+// wherever both reach dirPath. A pattern reaches dirPath either fully (its
+// only remaining requirement, past dirPath's components, is a trailing
+// "**", so it matches every possible descendant) or partially (it could
+// still match some descendants, e.g. a literal component further down the
+// pattern, but not necessarily all of them). The first full reach found
+// settles the outcome outright, since nothing earlier can still apply to
+// any path it covers. A partial, non-negated reach guarantees at least one
+// still-open descendant is included, since nothing with higher precedence
+// has already claimed the whole subtree. A partial, negated reach only
+// rules out some of the open paths, so scanning continues for one that
+// still includes the rest. prunesDir is conservative in that direction: it
+// only prunes once every pattern that could still apply to dirPath has
+// been accounted for this way and none of them leaves anything included.
+func (f Filter) prunesDir(dirPath string) bool {
+	if len(f) == 0 {
+		return false
+	}
+	dirSegs := strings.Split(dirPath, "/")
+	for i := len(f) - 1; i >= 0; i-- {
+		pattern := f[i]
+		negate := strings.HasPrefix(pattern, "!")
+		patternSegs := splitGlobPattern(strings.TrimPrefix(pattern, "!"))
+		switch dirReach(patternSegs, dirSegs) {
+		case reachNone:
+			continue
+		case reachFull:
+			return negate
+		case reachPartial:
+			if !negate {
+				return false
+			}
+			// A partial exclude only rules out some of the paths still
+			// open; keep scanning earlier patterns for one that still
+			// includes the rest.
+		}
+	}
+	return true
+}
+
+// dirReachKind classifies how much of the space of possible descendants of
+// a directory a pattern could still match, once the directory's own
+// components are accounted for.
+type dirReachKind int
+
+const (
+	// reachNone means the pattern can't match any descendant of dirPath:
+	// one of dirPath's components already fails to match the pattern.
+	reachNone dirReachKind = iota
+	// reachPartial means the pattern might match some descendants of
+	// dirPath, but not necessarily all of them (e.g. it still has a
+	// literal component, or a "**" encountered before dirPath's
+	// components were fully consumed, leaving its exact reach
+	// undetermined without knowing the real file names beneath it).
+	reachPartial
+	// reachFull means the pattern matches every possible descendant of
+	// dirPath: once dirPath's components are consumed, all that's left
+	// of the pattern is a trailing "**".
+	reachFull
+)
+
+// dirReach classifies patternSegs' reach over the descendants of the
+// directory described by dirSegs, consuming patternSegs against dirSegs
+// component by component.
+func dirReach(patternSegs, dirSegs []string) dirReachKind {
+	for _, seg := range dirSegs {
+		if len(patternSegs) == 0 {
+			return reachNone
+		}
+		if patternSegs[0] == "**" {
+			// "**" could consume any number of dirPath's remaining
+			// components before the rest of the pattern applies, so
+			// its exact reach over dirPath's descendants depends on
+			// names this function doesn't know; treat it as partial
+			// rather than assume it covers every descendant.
+			return reachPartial
+		}
+		ok, err := matchComponent(patternSegs[0], seg)
+		if err != nil || !ok {
+			return reachNone
+		}
+		patternSegs = patternSegs[1:]
+	}
+	switch {
+	case len(patternSegs) == 0:
+		// The pattern matches dirPath itself exactly, with nothing left
+		// to match a proper descendant's remaining components.
+		return reachNone
+	case len(patternSegs) == 1 && patternSegs[0] == "**":
+		return reachFull
+	default:
+		return reachPartial
+	}
+}
+
+// matchGlob matches a single gitignore-style pattern against path.
+// "**" matches zero or more path components; every other glob metacharacter
+// is matched within a single component, the same as filepath.Match.
+func matchGlob(pattern, path string) bool {
+	return matchSegments(splitGlobPattern(pattern), strings.Split(path, "/"))
+}
+
+// splitGlobPattern splits a pattern into path components. A trailing "/"
+// matches a directory and everything beneath it, so it's equivalent to
+// appending a "/**" to the pattern.
+func splitGlobPattern(pattern string) []string {
+	if strings.HasSuffix(pattern, "/") {
+		pattern = strings.TrimSuffix(pattern, "/") + "/**"
+	}
+	return strings.Split(pattern, "/")
+}
+
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		if matchSegments(pattern[1:], path) {
+			return true
+		}
+		if len(path) == 0 {
+			return false
+		}
+		return matchSegments(pattern, path[1:])
+	}
+	if len(path) == 0 {
+		return false
+	}
+	ok, err := matchComponent(pattern[0], path[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// matchComponent matches a single path component against a single pattern
+// component using shell-style wildcards (*, ?, [...]) via filepath.Match.
+// Components never contain separators, so Match's separator special-casing
+// never comes into play.
+func matchComponent(pattern, name string) (bool, error) {
+	return filepath.Match(pattern, name)
+}
+
+// ChecksumWildcard returns a stable digest over the subtree of the local
+// directory at path whose repo-relative entries match pattern, so callers
+// can cheaply ask "did anything I care about change" without running a
+// full diff. The digest is the SHA-256 of the sorted list of
+// "relpath:size:mtime" triples for every matched file.
+func ChecksumWildcard(path, pattern string) (string, error) {
+	filter := Filter{pattern}
+	var lines []string
+	err := WalkS3(path, func(p string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := toRelPath(path, p)
+		if !filter.Match(rel) {
+			return nil
+		}
+		lines = append(lines, fmt.Sprintf("%s:%d:%d", rel, info.Size(), info.ModTime().Unix()))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		_, _ = h.Write([]byte(line))
+		_, _ = h.Write([]byte{'\n'})
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,144 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Filter Filter
+		Path   string
+		Want   bool
+	}{
+		{Name: "empty filter matches everything", Filter: nil, Path: "a/b.txt", Want: true},
+		{Name: "no pattern matches -> excluded", Filter: Filter{"**/*.parquet"}, Path: "a/b.txt", Want: false},
+		{Name: "plain pattern includes a match", Filter: Filter{"**/*.parquet"}, Path: "a/b.parquet", Want: true},
+		{Name: "plain pattern includes nested match", Filter: Filter{"**/*.parquet"}, Path: "a/b/c.parquet", Want: true},
+		{
+			Name:   "negated pattern re-excludes under the included tree",
+			Filter: Filter{"**/*.parquet", "!_delta_log/**"},
+			Path:   "_delta_log/00.json",
+			Want:   false,
+		},
+		{
+			Name:   "negated pattern doesn't affect unrelated included paths",
+			Filter: Filter{"**/*.parquet", "!_delta_log/**"},
+			Path:   "data/part.parquet",
+			Want:   true,
+		},
+		{
+			Name:   "later rule overrides an earlier one for the same path",
+			Filter: Filter{"!important.log", "*.log"},
+			Path:   "important.log",
+			Want:   true,
+		},
+		{
+			Name:   "trailing slash matches the directory's contents",
+			Filter: Filter{"data/2024-*/"},
+			Path:   "data/2024-01/file.txt",
+			Want:   true,
+		},
+		{
+			Name:   "trailing slash doesn't match a sibling file",
+			Filter: Filter{"data/2024-*/"},
+			Path:   "data/2024-01.txt",
+			Want:   false,
+		},
+		{
+			Name:   "broad include still applies outside a narrower nested exclude",
+			Filter: Filter{"foo/**", "!foo/a/**"},
+			Path:   "foo/b.txt",
+			Want:   true,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			got := tt.Filter.Match(tt.Path)
+			if got != tt.Want {
+				t.Fatalf("Filter(%v).Match(%q) = %v, want %v", tt.Filter, tt.Path, got, tt.Want)
+			}
+		})
+	}
+}
+
+func TestFilterPrunesDir(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Filter  Filter
+		DirPath string
+		Want    bool
+	}{
+		{Name: "empty filter prunes nothing", Filter: nil, DirPath: "_delta_log", Want: false},
+		{
+			Name:    "excluded directory is pruned",
+			Filter:  Filter{"**/*.parquet", "!_delta_log/**"},
+			DirPath: "_delta_log",
+			Want:    true,
+		},
+		{
+			Name:    "directory that could still contain included files is not pruned",
+			Filter:  Filter{"**/*.parquet"},
+			DirPath: "data",
+			Want:    false,
+		},
+		{
+			Name:    "directory unreachable by any pattern prefix is pruned",
+			Filter:  Filter{"data/2024-*/"},
+			DirPath: "other",
+			Want:    true,
+		},
+		{
+			Name:    "a broad include is not pruned by a narrower, nested exclude",
+			Filter:  Filter{"foo/**", "!foo/a/**"},
+			DirPath: "foo",
+			Want:    false,
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			got := tt.Filter.prunesDir(tt.DirPath)
+			if got != tt.Want {
+				t.Fatalf("Filter(%v).prunesDir(%q) = %v, want %v", tt.Filter, tt.DirPath, got, tt.Want)
+			}
+		})
+	}
+}
+
+func TestChecksumWildcard(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, filepath.Join(dir, "data.parquet"), "parquet-data")
+	mustWriteFile(t, filepath.Join(dir, "notes.txt"), "unrelated")
+
+	matchAll, err := ChecksumWildcard(dir, "**/*.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matchNone, err := ChecksumWildcard(dir, "**/*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matchAll == matchNone {
+		t.Fatalf("expected distinct digests for a matching and a non-matching pattern, got %q for both", matchAll)
+	}
+
+	mustWriteFile(t, filepath.Join(dir, "notes.txt"), "changed but irrelevant to the pattern")
+	unaffected, err := ChecksumWildcard(dir, "**/*.parquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unaffected != matchAll {
+		t.Fatalf("expected digest to be unaffected by changes to files outside the pattern")
+	}
+}
+
+func mustWriteFile(t testing.TB, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
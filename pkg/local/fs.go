@@ -0,0 +1,51 @@
+package local
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FS is the subset of filesystem operations the local package needs to walk
+// and diff a checkout. It exists so that code built on top of WalkS3 and
+// DiffLocalWithHead can be tested against synthetic, in-memory trees, or run
+// against a non-POSIX backend (e.g. an import workflow backed by a tar or
+// mtree archive), without touching a real directory on disk.
+//
+// The default implementation, OSFS, simply delegates to the os and
+// path/filepath packages.
+type FS interface {
+	Open(name string) (fs.File, error)
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Chtimes(name string, atime, mtime time.Time) error
+	WalkDir(root string, fn fs.WalkDirFunc) error
+}
+
+// OSFS is the FS backed by the host's real filesystem.
+type OSFS struct{}
+
+func (OSFS) Open(name string) (fs.File, error) { return os.Open(name) }
+
+func (OSFS) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+
+func (OSFS) Lstat(name string) (fs.FileInfo, error) { return os.Lstat(name) }
+
+func (OSFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+
+func (OSFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+func (OSFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(root, fn)
+}
+
+// GetFS is threaded through DiffLocalWithHeadOptions and WalkS3FS so callers
+// can swap in a synthetic or non-POSIX FS for a given root. The default,
+// used whenever a caller doesn't set one, always returns OSFS.
+type GetFS func(root string) FS
+
+func defaultGetFS(string) FS { return OSFS{} }
@@ -0,0 +1,94 @@
+package local_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/require"
+	"github.com/treeverse/lakefs/pkg/api/apigen"
+	"github.com/treeverse/lakefs/pkg/local"
+)
+
+func TestOSFS(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("hello"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+
+	var osfs local.OSFS
+
+	f, err := osfs.Open(filePath)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	info, err := osfs.Stat(filePath)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), info.Size())
+
+	info, err = osfs.Lstat(filePath)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), info.Size())
+
+	entries, err := osfs.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	modTime := time.Unix(diffTestCorrectTime, 0)
+	require.NoError(t, osfs.Chtimes(filePath, modTime, modTime))
+	info, err = osfs.Stat(filePath)
+	require.NoError(t, err)
+	require.Equal(t, modTime.Unix(), info.ModTime().Unix())
+
+	var walked []string
+	require.NoError(t, osfs.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		require.NoError(t, err)
+		walked = append(walked, filepath.Base(path))
+		return nil
+	}))
+	require.ElementsMatch(t, []string{"sub", "a.txt"}, append(walked[:0:0], walked[1:]...))
+}
+
+// countingFS wraps local.OSFS so a test can prove DiffLocalWithHeadOptions.GetFS
+// is actually threaded all the way through to the content hashing done in
+// CompareContent mode, rather than that mode quietly falling back to the real
+// filesystem regardless of what GetFS returns.
+type countingFS struct {
+	local.OSFS
+	opens int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.opens++
+	return c.OSFS.Open(name)
+}
+
+func TestDiffLocalWithHeadOptsHonorsInjectedGetFS(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Unix(diffTestCorrectTime, 0)
+	path := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0o644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	fsys := &countingFS{}
+	remote := []apigen.ObjectStats{
+		{Path: "a.txt", SizeBytes: swag.Int64(5), Mtime: diffTestCorrectTime, Checksum: "sha256:mismatch"},
+	}
+	lc := make(chan apigen.ObjectStats, len(remote))
+	for _, o := range remote {
+		lc <- o
+	}
+	close(lc)
+
+	changes, err := local.DiffLocalWithHeadOpts(lc, dir, local.DiffLocalWithHeadOptions{
+		Compare: local.CompareContent,
+		GetFS:   func(string) local.FS { return fsys },
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, local.ChangeTypeModified, changes[0].Type)
+	require.Positive(t, fsys.opens, "content hashing in CompareContent mode must read through the injected GetFS, not the real filesystem directly")
+}
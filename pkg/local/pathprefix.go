@@ -0,0 +1,29 @@
+package local
+
+import "strings"
+
+// HasPathPrefix reports whether prefix is a path-component-aware prefix of
+// s: plain strings.HasPrefix treats "foo" as a prefix of "foobar", which is
+// wrong for path matching (skip/include/exclude logic that means to match
+// the directory "foo" must not also match the unrelated file "foobar").
+//
+// HasPathPrefix returns true only when prefix == s, when prefix ends in a
+// separator ("/") and s starts with it, or when s continues past prefix
+// with a separator. As special cases, "" matches everything and "/" (a
+// prefix made only of the separator) matches only absolute paths.
+//
+// Paths are always compared using "/" as the separator, matching the
+// repo-relative, slash-joined paths WalkS3 and Filter operate on, not the
+// host's filepath.Separator.
+func HasPathPrefix(s, prefix string) bool {
+	switch {
+	case prefix == "":
+		return true
+	case prefix == s:
+		return true
+	case strings.HasSuffix(prefix, "/"):
+		return strings.HasPrefix(s, prefix)
+	default:
+		return strings.HasPrefix(s, prefix) && len(s) > len(prefix) && s[len(prefix)] == '/'
+	}
+}
@@ -0,0 +1,33 @@
+package local
+
+import "testing"
+
+func TestHasPathPrefix(t *testing.T) {
+	cases := []struct {
+		Name   string
+		S      string
+		Prefix string
+		Want   bool
+	}{
+		{Name: "empty prefix matches everything", S: "a/b/c", Prefix: "", Want: true},
+		{Name: "empty prefix matches empty", S: "", Prefix: "", Want: true},
+		{Name: "root prefix matches absolute path", S: "/a/b", Prefix: "/", Want: true},
+		{Name: "root prefix rejects relative path", S: "a/b", Prefix: "/", Want: false},
+		{Name: "exact match", S: "a/b", Prefix: "a/b", Want: true},
+		{Name: "trailing slash prefix matches child", S: "a/b/c", Prefix: "a/b/", Want: true},
+		{Name: "component boundary matches", S: "a/b/c", Prefix: "a/b", Want: true},
+		{Name: "foo is not a prefix of foobar", S: "foobar", Prefix: "foo", Want: false},
+		{Name: "foobar is not a prefix of foo", S: "foo", Prefix: "foobar", Want: false},
+		{Name: "sibling directory is not a prefix", S: "ab/c", Prefix: "a", Want: false},
+		{Name: "prefix longer than s", S: "a", Prefix: "a/b", Want: false},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.Name, func(t *testing.T) {
+			got := HasPathPrefix(tt.S, tt.Prefix)
+			if got != tt.Want {
+				t.Fatalf("HasPathPrefix(%q, %q) = %v, want %v", tt.S, tt.Prefix, got, tt.Want)
+			}
+		})
+	}
+}
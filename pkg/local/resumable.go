@@ -0,0 +1,151 @@
+package local
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StateDir is the directory, relative to a checkout's root, that resumable
+// operation journals are persisted under.
+const StateDir = ".lakefs/state"
+
+// ResumeStatus is the last known state of a single path within a resumable
+// operation.
+type ResumeStatus string
+
+const (
+	ResumeStatusInProgress ResumeStatus = "in-progress"
+	ResumeStatusDone       ResumeStatus = "done"
+)
+
+// ResumeEntry is a single line of a resume journal: the decision made (or
+// being made) for one path during one operation.
+type ResumeEntry struct {
+	Path       string       `json:"path"`
+	Action     string       `json:"action"`
+	RemoteETag string       `json:"remote_etag,omitempty"`
+	LocalSize  int64        `json:"local_size,omitempty"`
+	Status     ResumeStatus `json:"status"`
+}
+
+// ResumeToken tracks the progress of one interruptible operation (e.g. one
+// `lakectl local pull`) against an append-only journal on disk, so that if
+// the process is killed mid-walk, a later invocation using the same op-id
+// can pick up where it left off instead of redoing already-completed work.
+//
+// The journal is append-only by design: CommitProgress never rewrites
+// earlier lines, it just appends a newer entry for the same path, and
+// replay keeps the last entry seen per path. That makes the journal safe to
+// keep appending to even if a previous run was killed mid-write.
+type ResumeToken struct {
+	mu      sync.Mutex
+	opID    string
+	dir     string
+	entries map[string]ResumeEntry
+	f       *os.File
+}
+
+func journalPath(root, opID string) string {
+	return filepath.Join(root, StateDir, opID+".jsonl")
+}
+
+// NewResumeToken opens (or creates) the journal for opID under root,
+// replaying any entries already recorded so CommitProgress and Entry
+// reflect a prior, interrupted run of the same operation.
+func NewResumeToken(root, opID string) (*ResumeToken, error) {
+	dir := filepath.Join(root, StateDir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create state dir: %w", err)
+	}
+
+	t := &ResumeToken{opID: opID, dir: dir, entries: map[string]ResumeEntry{}}
+
+	path := journalPath(root, opID)
+	if existing, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			var entry ResumeEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue // tolerate a torn last line from a killed process
+			}
+			t.entries[entry.Path] = entry
+		}
+		_ = existing.Close()
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+	t.f = f
+	return t, nil
+}
+
+// Entry returns the last recorded state of path, if any.
+func (t *ResumeToken) Entry(path string) (ResumeEntry, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.entries[path]
+	return entry, ok
+}
+
+// IsDone reports whether path was already recorded ResumeStatusDone against
+// remoteETag by a prior, interrupted run. Callers use this to skip redoing
+// work (e.g. re-hashing a file's content) for a path the previous run
+// already confirmed, not just to skip re-reporting it.
+func (t *ResumeToken) IsDone(path, remoteETag string) bool {
+	entry, ok := t.Entry(path)
+	return ok && entry.Status == ResumeStatusDone && entry.RemoteETag == remoteETag
+}
+
+// Record appends entry to the journal and updates the in-memory view
+// returned by Entry. Callers mark a path ResumeStatusInProgress before
+// starting a transfer and ResumeStatusDone after it completes, via
+// CommitProgress.
+func (t *ResumeToken) Record(entry ResumeEntry) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := t.f.Write(line); err != nil {
+		return fmt.Errorf("append journal entry for %q: %w", entry.Path, err)
+	}
+	if err := t.f.Sync(); err != nil {
+		return fmt.Errorf("sync journal after %q: %w", entry.Path, err)
+	}
+	t.entries[entry.Path] = entry
+	return nil
+}
+
+// CommitProgress marks path as done within this operation.
+func CommitProgress(token *ResumeToken, path string) error {
+	entry, _ := token.Entry(path)
+	entry.Path = path
+	entry.Status = ResumeStatusDone
+	return token.Record(entry)
+}
+
+// AbortAndCleanup closes token's journal and removes it from disk. Callers
+// use this once an operation finishes successfully with nothing left to
+// resume; on failure, the journal is left in place so a later run can
+// resume from it.
+func AbortAndCleanup(token *ResumeToken) error {
+	token.mu.Lock()
+	defer token.mu.Unlock()
+	if err := token.f.Close(); err != nil {
+		return fmt.Errorf("close journal: %w", err)
+	}
+	path := filepath.Join(token.dir, token.opID+".jsonl")
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package local_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/swag"
+	"github.com/stretchr/testify/require"
+	"github.com/treeverse/lakefs/pkg/api/apigen"
+	"github.com/treeverse/lakefs/pkg/local"
+)
+
+func TestResumeTokenRoundTripsAcrossProcesses(t *testing.T) {
+	dir := t.TempDir()
+
+	token, err := local.NewResumeToken(dir, "op1")
+	require.NoError(t, err)
+	require.NoError(t, token.Record(local.ResumeEntry{Path: "a.txt", Action: "modified", RemoteETag: "etag-a", Status: local.ResumeStatusInProgress}))
+	require.NoError(t, token.Record(local.ResumeEntry{Path: "a.txt", Action: "modified", RemoteETag: "etag-a", Status: local.ResumeStatusDone}))
+	require.NoError(t, token.Record(local.ResumeEntry{Path: "b.txt", Action: "added", Status: local.ResumeStatusInProgress}))
+
+	// Simulate the process being killed before b.txt's CommitProgress: a new
+	// ResumeToken opened against the same journal must see a.txt as Done and
+	// b.txt as still InProgress.
+	resumed, err := local.NewResumeToken(dir, "op1")
+	require.NoError(t, err)
+
+	entry, ok := resumed.Entry("a.txt")
+	require.True(t, ok)
+	require.Equal(t, local.ResumeStatusDone, entry.Status)
+
+	entry, ok = resumed.Entry("b.txt")
+	require.True(t, ok)
+	require.Equal(t, local.ResumeStatusInProgress, entry.Status)
+}
+
+func TestResumeTokenToleratesTornLastLine(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := filepath.Join(dir, local.StateDir)
+	require.NoError(t, os.MkdirAll(stateDir, 0o755))
+
+	// A process killed mid-write can leave a partial last line in the
+	// append-only journal; it must be skipped rather than failing the load.
+	journal := `{"path":"a.txt","action":"modified","remote_etag":"etag-a","status":"done"}
+{"path":"b.txt","action":"added","stat`
+	require.NoError(t, os.WriteFile(filepath.Join(stateDir, "op1.jsonl"), []byte(journal), 0o644))
+
+	token, err := local.NewResumeToken(dir, "op1")
+	require.NoError(t, err)
+
+	entry, ok := token.Entry("a.txt")
+	require.True(t, ok)
+	require.Equal(t, local.ResumeStatusDone, entry.Status)
+
+	_, ok = token.Entry("b.txt")
+	require.False(t, ok, "a torn last line must not be replayed")
+}
+
+func TestDiffLocalWithHeadOptsSkipsHashingPathsConfirmedDoneByAPriorRun(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Unix(diffTestCorrectTime, 0)
+
+	writePath := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(writePath, []byte("hello"), 0o644))
+	require.NoError(t, os.Chtimes(writePath, modTime, modTime))
+
+	token, err := local.NewResumeToken(dir, "op1")
+	require.NoError(t, err)
+	// Simulate a prior, interrupted run that already pulled a.txt at
+	// checksum "etag-a" and committed it as done.
+	require.NoError(t, token.Record(local.ResumeEntry{Path: "a.txt", Action: "modified", RemoteETag: "etag-a", Status: local.ResumeStatusDone}))
+
+	remote := []apigen.ObjectStats{
+		// Deliberately disagrees with the local file's real size and mtime:
+		// if the resume confirmation weren't honored, isModified would
+		// report this as Modified on the size check alone.
+		{Path: "a.txt", SizeBytes: swag.Int64(999), Mtime: diffTestCorrectTime + 1000, Checksum: "etag-a"},
+	}
+	lc := make(chan apigen.ObjectStats, len(remote))
+	for _, o := range remote {
+		lc <- o
+	}
+	close(lc)
+
+	changes, err := local.DiffLocalWithHeadOpts(lc, dir, local.DiffLocalWithHeadOptions{
+		Compare: local.CompareContent,
+		Resume:  token,
+	})
+	require.NoError(t, err)
+
+	require.Empty(t, changes, "a path already confirmed done against the same remote checksum must not be re-reported or re-hashed")
+}
@@ -0,0 +1,102 @@
+package local
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+)
+
+// lakeFSDir is the directory lakeFS itself uses for bookkeeping (the
+// contenthash cache, resumable-operation journals, ...) within a local
+// checkout. WalkS3 never descends into it: it's not part of the tracked
+// tree and must never show up as an added file in a diff.
+const lakeFSDir = ".lakefs"
+
+// WalkS3 walks the directory tree rooted at root on the real filesystem, in
+// the same lexicographic order that an S3 ListObjectsV2 call would return
+// the equivalent keys in. It's WalkS3FS(root, OSFS{}, walkFn).
+func WalkS3(root string, walkFn filepath.WalkFunc) error {
+	return WalkS3FS(root, OSFS{}, walkFn)
+}
+
+// WalkS3FS is WalkS3 against an arbitrary FS, so callers can walk synthetic
+// trees (tests) or non-POSIX backends. walkFn is invoked for every regular
+// file; directories themselves, and anything under lakeFSDir, are never
+// passed to it.
+//
+// A plain recursive walk sorts entries within a directory by name alone,
+// which disagrees with S3's flat key ordering whenever one entry's name is a
+// prefix of a sibling's (e.g. a directory "imported" and a file
+// "imported.txt" sort as "imported" < "imported.txt", while the equivalent
+// S3 keys "imported/..." and "imported.txt" sort the other way, because '.'
+// sorts before '/'). WalkS3FS corrects for this by appending the path
+// separator to directory names before comparing, so that within a directory
+// listing, entries sort exactly as their full S3 keys would.
+func WalkS3FS(root string, fsys FS, walkFn filepath.WalkFunc) error {
+	return walkS3Root(root, fsys, nil, walkFn)
+}
+
+// shouldDescendFunc decides, given a directory's repo-relative path, whether
+// walkS3 should recurse into it at all. Returning false skips it (and
+// everything beneath it) without ever calling walkFn for its contents. It's
+// how WalkS3FilteredFS prunes excluded directories and DiffLocalWithHeadOpts
+// skips subtrees a resumed run already finished.
+type shouldDescendFunc func(relPath string) bool
+
+func walkS3Root(root string, fsys FS, shouldDescend shouldDescendFunc, walkFn filepath.WalkFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		return walkFn(root, nil, err)
+	}
+	return walkS3(fsys, root, "", info, shouldDescend, walkFn)
+}
+
+func walkS3(fsys FS, path, relPath string, info fs.FileInfo, shouldDescend shouldDescendFunc, walkFn filepath.WalkFunc) error {
+	if HasPathPrefix(relPath, lakeFSDir) {
+		return nil
+	}
+	if !info.IsDir() {
+		return walkFn(path, info, nil)
+	}
+	if relPath != "" && shouldDescend != nil && !shouldDescend(relPath) {
+		return nil
+	}
+
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		return walkFn(path, info, err)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return s3SortKey(entries[i]) < s3SortKey(entries[j])
+	})
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(path, entry.Name())
+		entryRelPath := entry.Name()
+		if relPath != "" {
+			entryRelPath = relPath + "/" + entry.Name()
+		}
+		entryInfo, err := entry.Info()
+		if err != nil {
+			if err := walkFn(entryPath, entryInfo, err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := walkS3(fsys, entryPath, entryRelPath, entryInfo, shouldDescend, walkFn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// s3SortKey returns the key a directory entry should be compared by in
+// order to reproduce S3's flat lexicographic ordering of keys: directories
+// sort as if their name were followed by the path separator, since every
+// key beneath them will be.
+func s3SortKey(entry fs.DirEntry) string {
+	if entry.IsDir() {
+		return entry.Name() + string(filepath.Separator)
+	}
+	return entry.Name()
+}
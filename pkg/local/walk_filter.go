@@ -0,0 +1,33 @@
+package local
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// WalkS3Filtered is WalkS3 restricted to paths matched by filter. Entries
+// excluded by filter are never passed to walkFn; directories filter can
+// prove have nothing matching beneath them (see Filter.prunesDir) are
+// skipped without being descended into at all.
+func WalkS3Filtered(root string, filter Filter, walkFn filepath.WalkFunc) error {
+	return WalkS3FilteredFS(root, OSFS{}, filter, walkFn)
+}
+
+// WalkS3FilteredFS is WalkS3Filtered against an arbitrary FS.
+func WalkS3FilteredFS(root string, fsys FS, filter Filter, walkFn filepath.WalkFunc) error {
+	if len(filter) == 0 {
+		return WalkS3FS(root, fsys, walkFn)
+	}
+	shouldDescend := func(relPath string) bool {
+		return !filter.prunesDir(relPath)
+	}
+	return walkS3Root(root, fsys, shouldDescend, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return walkFn(path, info, err)
+		}
+		if !filter.Match(toRelPath(root, path)) {
+			return nil
+		}
+		return walkFn(path, info, nil)
+	})
+}